@@ -0,0 +1,98 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+// Package chunk splits Chromaprint fingerprints into variable-length,
+// content-defined chunks, so that long fingerprints sharing large runs of
+// near-identical hashes (e.g. re-encodes of the same recording) can share
+// storage instead of being stored whole.
+package chunk
+
+import (
+	"github.com/acoustid/go-acoustid/chromaprint"
+)
+
+const (
+	// windowSize is the number of hashes the rolling hash is computed over.
+	windowSize = 8
+
+	// minChunkHashes and maxChunkHashes bound the size of a single chunk.
+	minChunkHashes = 16
+	maxChunkHashes = 256
+
+	// avgChunkHashes is the target average chunk size; chunkMask is derived
+	// from it so that, on average, one in avgChunkHashes hashes is a boundary.
+	avgChunkHashes = 64
+	chunkMask      = avgChunkHashes - 1
+)
+
+// buzhashTable holds fixed pseudo-random values used to mix a hash into the
+// rolling window. It is indexed by foldByte, which folds a 32-bit hash down
+// to a single byte.
+var buzhashTable = newBuzhashTable()
+
+func newBuzhashTable() (table [256]uint32) {
+	seed := uint32(0x2545f491)
+	for i := range table {
+		// xorshift32
+		seed ^= seed << 13
+		seed ^= seed >> 17
+		seed ^= seed << 5
+		table[i] = seed
+	}
+	return
+}
+
+func foldByte(h uint32) byte {
+	return byte(h) ^ byte(h>>8) ^ byte(h>>16) ^ byte(h>>24)
+}
+
+func rotl(x uint32, n uint) uint32 {
+	return (x << n) | (x >> (32 - n))
+}
+
+// Chunk is a variable-length, content-defined run of a Fingerprint's hashes.
+type Chunk struct {
+	Offset      int
+	Hashes      []uint32
+	RollingHash uint32
+}
+
+// ChunkFingerprint splits fp's hashes into content-defined chunks. See ChunkHashes.
+func ChunkFingerprint(fp *chromaprint.Fingerprint) []Chunk {
+	return ChunkHashes(fp.Hashes)
+}
+
+// ChunkHashes splits hashes into content-defined chunks using a Buzhash-style
+// rolling hash over a window of windowSize hashes. A boundary is cut whenever
+// the rolling hash's low bits are all zero, targeting an average chunk size
+// of avgChunkHashes hashes, bounded by minChunkHashes and maxChunkHashes.
+// The final chunk holds whatever is left of the input and may be shorter
+// than minChunkHashes.
+func ChunkHashes(hashes []uint32) []Chunk {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	var chunks []Chunk
+	start := 0
+	var h uint32
+	for i, v := range hashes {
+		h = rotl(h, 1) ^ buzhashTable[foldByte(v)]
+		if i >= windowSize {
+			out := hashes[i-windowSize]
+			h ^= rotl(buzhashTable[foldByte(out)], windowSize)
+		}
+
+		size := i - start + 1
+		last := i == len(hashes)-1
+		if last || size >= maxChunkHashes || (size >= minChunkHashes && h&chunkMask == 0) {
+			chunks = append(chunks, Chunk{
+				Offset:      start,
+				Hashes:      hashes[start : i+1],
+				RollingHash: h,
+			})
+			start = i + 1
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package chunk
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomHashes(n int, seed int64) []uint32 {
+	r := rand.New(rand.NewSource(seed))
+	hashes := make([]uint32, n)
+	for i := range hashes {
+		hashes[i] = r.Uint32()
+	}
+	return hashes
+}
+
+func TestChunkHashes_Empty(t *testing.T) {
+	assert.Nil(t, ChunkHashes(nil))
+}
+
+func TestChunkHashes_Bounds(t *testing.T) {
+	hashes := randomHashes(5000, 1)
+	chunks := ChunkHashes(hashes)
+	require.NotEmpty(t, chunks)
+
+	total := 0
+	for i, c := range chunks {
+		assert.LessOrEqual(t, len(c.Hashes), maxChunkHashes)
+		if i < len(chunks)-1 {
+			// Only the final chunk is allowed to be short, since it is
+			// whatever is left over once the input runs out.
+			assert.GreaterOrEqual(t, len(c.Hashes), minChunkHashes)
+		}
+		assert.Equal(t, total, c.Offset)
+		total += len(c.Hashes)
+	}
+	assert.Equal(t, len(hashes), total)
+}
+
+func TestChunkHashes_Deterministic(t *testing.T) {
+	hashes := randomHashes(5000, 2)
+	assert.Equal(t, ChunkHashes(hashes), ChunkHashes(hashes))
+}
+
+// chunkContentKey identifies a chunk by its hash content, ignoring where it
+// landed in the input.
+func chunkContentKey(c Chunk) string {
+	return fmt.Sprint(c.Hashes)
+}
+
+// TestChunkHashes_ResyncAfterInsertion checks the defining property of
+// content-defined chunking: inserting data at the front of a fingerprint
+// should not change every downstream chunk, only the ones near the
+// insertion point. The chunker should resynchronize with the unmodified
+// chunking of the shared suffix soon after.
+func TestChunkHashes_ResyncAfterInsertion(t *testing.T) {
+	suffix := randomHashes(5000, 3)
+	prefix := randomHashes(37, 4)
+	shifted := append(append([]uint32{}, prefix...), suffix...)
+
+	chunksA := ChunkHashes(suffix)
+	chunksB := ChunkHashes(shifted)
+
+	seen := make(map[string]bool, len(chunksA))
+	for _, c := range chunksA {
+		seen[chunkContentKey(c)] = true
+	}
+
+	shared := 0
+	for _, c := range chunksB {
+		if seen[chunkContentKey(c)] {
+			shared++
+		}
+	}
+
+	assert.Greater(t, shared, 0, "expected at least one chunk to survive the prefix insertion unchanged")
+	assert.Greater(t, shared, len(chunksA)/2, "expected most of the shared suffix to resync after one chunk")
+}
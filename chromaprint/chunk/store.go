@@ -0,0 +1,111 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"github.com/acoustid/go-acoustid/index/vfs"
+)
+
+// ChunkStore persists chunk contents keyed by their content hash, so that
+// identical chunks shared across duplicate or near-duplicate fingerprints are
+// only ever stored once.
+type ChunkStore interface {
+	// Has reports whether a chunk with the given key is already stored.
+	Has(key string) (bool, error)
+
+	// Put stores a chunk's hashes under key. It is a no-op if key is already stored.
+	Put(key string, hashes []uint32) error
+
+	// Get retrieves a previously stored chunk's hashes by key.
+	Get(key string) ([]uint32, error)
+}
+
+// Key returns the content-hash key under which a chunk with the given hashes
+// should be stored.
+func Key(hashes []uint32) string {
+	digest := sha256.New()
+	var buf [4]byte
+	for _, h := range hashes {
+		binary.BigEndian.PutUint32(buf[:], h)
+		digest.Write(buf[:])
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// FileChunkStore is a ChunkStore backed by a vfs.FileSystem, storing each
+// chunk as a file named after its content-hash key.
+type FileChunkStore struct {
+	fs vfs.FileSystem
+}
+
+// NewFileChunkStore returns a FileChunkStore that stores chunks in fs.
+func NewFileChunkStore(fs vfs.FileSystem) *FileChunkStore {
+	return &FileChunkStore{fs: fs}
+}
+
+func (s *FileChunkStore) Has(key string) (bool, error) {
+	f, err := s.fs.OpenFile(key)
+	if err != nil {
+		if vfs.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to check chunk")
+	}
+	f.Close()
+	return true, nil
+}
+
+func (s *FileChunkStore) Put(key string, hashes []uint32) error {
+	exists, err := s.Has(key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	out, err := s.fs.CreateAtomicFile(key)
+	if err != nil {
+		return errors.Wrap(err, "failed to create chunk")
+	}
+	defer out.Close()
+
+	buf := make([]byte, len(hashes)*4)
+	for i, h := range hashes {
+		binary.BigEndian.PutUint32(buf[i*4:], h)
+	}
+	if _, err := out.Write(buf); err != nil {
+		return errors.Wrap(err, "failed to write chunk")
+	}
+	if err := out.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync chunk")
+	}
+	return out.Commit()
+}
+
+func (s *FileChunkStore) Get(key string) ([]uint32, error) {
+	f, err := s.fs.OpenFile(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open chunk")
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read chunk")
+	}
+
+	hashes := make([]uint32, len(data)/4)
+	for i := range hashes {
+		hashes[i] = binary.BigEndian.Uint32(data[i*4:])
+	}
+	return hashes, nil
+}
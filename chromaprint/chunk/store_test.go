@@ -0,0 +1,64 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package chunk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acoustid/go-acoustid/index/vfs"
+)
+
+func TestFileChunkStore_RoundTrip(t *testing.T) {
+	fs := vfs.NewMemFS()
+	store := NewFileChunkStore(fs)
+
+	hashes := randomHashes(100, 1)
+	key := Key(hashes)
+
+	exists, err := store.Has(key)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, store.Put(key, hashes))
+
+	exists, err = store.Has(key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	got, err := store.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, hashes, got)
+}
+
+func TestFileChunkStore_PutIsIdempotent(t *testing.T) {
+	fs := vfs.NewMemFS()
+	store := NewFileChunkStore(fs)
+
+	hashes := randomHashes(10, 2)
+	key := Key(hashes)
+
+	require.NoError(t, store.Put(key, hashes))
+	require.NoError(t, store.Put(key, hashes))
+
+	got, err := store.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, hashes, got)
+}
+
+func TestKey_Deterministic(t *testing.T) {
+	hashes := randomHashes(50, 3)
+	assert.Equal(t, Key(hashes), Key(hashes))
+}
+
+func TestKey_DifferentHashesDifferentKeys(t *testing.T) {
+	seen := make(map[string]bool)
+	for seed := int64(0); seed < 10; seed++ {
+		key := Key(randomHashes(50, seed))
+		assert.False(t, seen[key], "collision for seed %d", seed)
+		seen[key] = true
+	}
+}
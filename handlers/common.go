@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"encoding/json"
 	"encoding/xml"
+	"regexp"
 )
 
 type ErrorResponse struct {
@@ -29,21 +30,80 @@ func NewErrorResponse(message string, code int) interface{} {
 	}
 }
 
-func MarshalResponse(response interface{}, format ResponseFormat) ([]byte, error) {
+// defaultJsonpCallback is used when the request does not specify a callback name.
+const defaultJsonpCallback = "jsonp"
+
+// jsonpCallbackRegexp restricts callback names to letters, digits, '_', '$'
+// and '.' (the dot allows callbacks like "foo.bar").
+var jsonpCallbackRegexp = regexp.MustCompile(`^[A-Za-z0-9_$.]+$`)
+
+// parseCallback reads the JSONP callback name from the "callback" (or "jsoncallback")
+// query parameter, falling back to defaultJsonpCallback when neither is set.
+func parseCallback(values url.Values) (string, error) {
+	callback := values.Get("callback")
+	if callback == "" {
+		callback = values.Get("jsoncallback")
+	}
+	if callback == "" {
+		return defaultJsonpCallback, nil
+	}
+	if !jsonpCallbackRegexp.MatchString(callback) {
+		return "", fmt.Errorf("invalid callback %q", callback)
+	}
+	return callback, nil
+}
+
+func MarshalResponse(response interface{}, format ResponseFormat, callback string) ([]byte, error) {
 	switch format {
 	case JsonFormat:
 		return json.Marshal(response)
+	case JsonpFormat:
+		data, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 0, len(callback)+len(data)+2)
+		buf = append(buf, callback...)
+		buf = append(buf, '(')
+		buf = append(buf, data...)
+		buf = append(buf, ')', ';')
+		return buf, nil
 	case XmlFormat:
 		return xml.Marshal(response)
 	}
 	return nil, fmt.Errorf("unsupported format")
 }
 
-func WriteResponse(w http.ResponseWriter, status int, response interface{}, format ResponseFormat) {
-	data, _ := MarshalResponse(response, format)
+// WriteResponseFromRequest resolves the response format and, for JSONP, the
+// callback name from values, then writes response to w in that format. If
+// values requests an unsupported format or an invalid callback, an
+// ErrorResponse is written instead with a 400 status.
+func WriteResponseFromRequest(w http.ResponseWriter, status int, response interface{}, values url.Values, allowed ResponseFormat) {
+	format, err := parseResponseFormat(values, allowed)
+	if err != nil {
+		WriteResponse(w, http.StatusBadRequest, NewErrorResponse(err.Error(), http.StatusBadRequest), JsonFormat, "")
+		return
+	}
+
+	var callback string
+	if format == JsonpFormat {
+		callback, err = parseCallback(values)
+		if err != nil {
+			WriteResponse(w, http.StatusBadRequest, NewErrorResponse(err.Error(), http.StatusBadRequest), JsonFormat, "")
+			return
+		}
+	}
+
+	WriteResponse(w, status, response, format, callback)
+}
+
+func WriteResponse(w http.ResponseWriter, status int, response interface{}, format ResponseFormat, callback string) {
+	data, _ := MarshalResponse(response, format, callback)
 	switch format {
 	case JsonFormat:
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	case JsonpFormat:
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
 	case XmlFormat:
 		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
 	}
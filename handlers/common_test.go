@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteResponseFromRequest_Jsonp(t *testing.T) {
+	w := httptest.NewRecorder()
+	values := url.Values{"format": {"jsonp"}, "callback": {"foo.bar"}}
+	WriteResponseFromRequest(w, 200, map[string]string{"status": "ok"}, values, JsonpFormat)
+
+	assert.Equal(t, "application/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `foo.bar({"status":"ok"});`, w.Body.String())
+}
+
+func TestWriteResponseFromRequest_JsonpDefaultCallback(t *testing.T) {
+	w := httptest.NewRecorder()
+	values := url.Values{"format": {"jsonp"}}
+	WriteResponseFromRequest(w, 200, map[string]string{"status": "ok"}, values, JsonpFormat)
+
+	assert.Equal(t, `jsonp({"status":"ok"});`, w.Body.String())
+}
+
+func TestWriteResponseFromRequest_InvalidCallback(t *testing.T) {
+	w := httptest.NewRecorder()
+	values := url.Values{"format": {"jsonp"}, "callback": {"not valid!"}}
+	WriteResponseFromRequest(w, 200, map[string]string{"status": "ok"}, values, JsonpFormat)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"error"`)
+}
+
+func TestWriteResponseFromRequest_UnsupportedFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	values := url.Values{"format": {"jsonp"}}
+	WriteResponseFromRequest(w, 200, map[string]string{"status": "ok"}, values, JsonFormat)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"error"`)
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+// Package cache implements a bounded-size LRU cache of decoded index.Item blocks,
+// so that repeated lookups against the same term range don't have to re-read and
+// re-decode the same blocks from disk.
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/acoustid/go-acoustid/index"
+)
+
+// itemSize is the approximate in-memory size of a single index.Item, used to
+// size the cache in bytes rather than in number of blocks.
+const itemSize = 8
+
+// blockKey identifies a decoded block of items within a segment.
+type blockKey struct {
+	segmentID string
+	offset    int
+}
+
+type entry struct {
+	key   blockKey
+	items []index.Item
+}
+
+// Cache is a bounded-size, thread-safe LRU cache of decoded index.Item blocks,
+// keyed by (segmentID, blockOffset).
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int
+	curBytes int
+	ll       *list.List
+	elements map[blockKey]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+// NewCache creates a Cache that evicts least-recently-used blocks once the
+// approximate total size of cached blocks exceeds maxBytes.
+func NewCache(maxBytes int) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		elements: make(map[blockKey]*list.Element),
+	}
+}
+
+// Get returns the cached block for (segmentID, offset), if present.
+func (c *Cache) Get(segmentID string, offset int) ([]index.Item, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.elements[blockKey{segmentID, offset}]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*entry).items, true
+}
+
+// Put inserts a decoded block into the cache, evicting the least-recently-used
+// blocks as needed to stay within maxBytes.
+func (c *Cache) Put(segmentID string, offset int, items []index.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := blockKey{segmentID, offset}
+	if elem, ok := c.elements[key]; ok {
+		c.curBytes += (len(items) - len(elem.Value.(*entry).items)) * itemSize
+		elem.Value = &entry{key: key, items: items}
+		c.ll.MoveToFront(elem)
+	} else {
+		elem := c.ll.PushFront(&entry{key: key, items: items})
+		c.elements[key] = elem
+		c.curBytes += len(items) * itemSize
+	}
+
+	for c.curBytes > c.maxBytes {
+		back := c.ll.Back()
+		if back == nil {
+			break
+		}
+		c.removeElement(back)
+	}
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	e := elem.Value.(*entry)
+	delete(c.elements, e.key)
+	c.curBytes -= len(e.items) * itemSize
+}
+
+// Len returns the number of blocks currently held in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Hits returns the number of Get calls that found a cached block.
+func (c *Cache) Hits() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// Misses returns the number of Get calls that found no cached block.
+func (c *Cache) Misses() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.misses
+}
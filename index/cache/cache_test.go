@@ -0,0 +1,120 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package cache
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acoustid/go-acoustid/index"
+)
+
+func TestCache_GetPutHitMiss(t *testing.T) {
+	c := NewCache(1024)
+
+	_, ok := c.Get("seg", 0)
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, c.Misses())
+	assert.EqualValues(t, 0, c.Hits())
+
+	items := []index.Item{{Term: 1, DocID: 1}, {Term: 2, DocID: 1}}
+	c.Put("seg", 0, items)
+
+	got, ok := c.Get("seg", 0)
+	assert.True(t, ok)
+	assert.Equal(t, items, got)
+	assert.EqualValues(t, 1, c.Hits())
+	assert.EqualValues(t, 1, c.Misses())
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// itemSize is 8 bytes, so a 1-item block costs 8 bytes; cap at 16 bytes
+	// keeps at most two blocks around.
+	c := NewCache(2 * itemSize)
+
+	c.Put("seg", 0, []index.Item{{Term: 0, DocID: 0}})
+	c.Put("seg", 1, []index.Item{{Term: 1, DocID: 0}})
+	require.Equal(t, 2, c.Len())
+
+	// Touch block 0 so block 1 becomes the least-recently-used entry.
+	_, ok := c.Get("seg", 0)
+	require.True(t, ok)
+
+	c.Put("seg", 2, []index.Item{{Term: 2, DocID: 0}})
+	assert.Equal(t, 2, c.Len())
+
+	_, ok = c.Get("seg", 1)
+	assert.False(t, ok, "least-recently-used block should have been evicted")
+
+	_, ok = c.Get("seg", 0)
+	assert.True(t, ok, "recently-touched block should still be cached")
+
+	_, ok = c.Get("seg", 2)
+	assert.True(t, ok, "just-inserted block should still be cached")
+}
+
+// fakeBlockReader is a test-only BlockReader backed by an in-memory slice of
+// blocks, counting how many times ReadBlock and SkipBlock are called.
+type fakeBlockReader struct {
+	segmentID string
+	blocks    [][]index.Item
+	pos       int
+	offset    int
+
+	readCalls int
+	skipCalls int
+}
+
+func (r *fakeBlockReader) SegmentID() string { return r.segmentID }
+func (r *fakeBlockReader) Offset() int       { return r.offset }
+
+func (r *fakeBlockReader) ReadBlock() ([]index.Item, error) {
+	r.readCalls++
+	if r.pos >= len(r.blocks) {
+		return nil, io.EOF
+	}
+	block := r.blocks[r.pos]
+	r.pos++
+	r.offset += len(block)
+	return block, nil
+}
+
+func (r *fakeBlockReader) SkipBlock(n int) error {
+	r.skipCalls++
+	r.offset += n
+	r.pos++
+	return nil
+}
+
+func TestCachingItemReader_MissThenHitSkips(t *testing.T) {
+	reader := &fakeBlockReader{
+		segmentID: "seg",
+		blocks: [][]index.Item{
+			{{Term: 1, DocID: 1}, {Term: 2, DocID: 1}},
+		},
+	}
+	c := NewCache(1024)
+	cr := NewCachingItemReader(reader, c)
+
+	items, err := cr.ReadBlock()
+	require.NoError(t, err)
+	assert.Equal(t, reader.blocks[0], items)
+	assert.Equal(t, 1, reader.readCalls)
+	assert.Equal(t, 0, reader.skipCalls)
+
+	// Read the same block again through a second reader sharing the cache,
+	// positioned at the same offset: it should hit the cache and skip
+	// instead of decoding.
+	reader2 := &fakeBlockReader{segmentID: "seg"}
+	cr2 := NewCachingItemReader(reader2, c)
+
+	items2, err := cr2.ReadBlock()
+	require.NoError(t, err)
+	assert.Equal(t, reader.blocks[0], items2)
+	assert.Equal(t, 0, reader2.readCalls)
+	assert.Equal(t, 1, reader2.skipCalls)
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package cache
+
+import (
+	"io"
+
+	"github.com/acoustid/go-acoustid/index"
+)
+
+// BlockReader is an index.ItemReader whose blocks are addressable by a stable
+// offset within a named segment, so their decoded contents can be cached and
+// later skipped without re-reading.
+type BlockReader interface {
+	index.ItemReader
+
+	// SegmentID identifies the segment this reader reads from.
+	SegmentID() string
+
+	// Offset returns the position that will be read by the next ReadBlock call.
+	Offset() int
+
+	// SkipBlock advances the reader past a block of n items without decoding it.
+	SkipBlock(n int) error
+}
+
+// CachingItemReader wraps a BlockReader, serving blocks from a Cache where
+// possible and populating the Cache on a miss.
+type CachingItemReader struct {
+	reader BlockReader
+	cache  *Cache
+}
+
+// NewCachingItemReader returns an index.ItemReader that consults cache before
+// reading and decoding a block from reader.
+func NewCachingItemReader(reader BlockReader, cache *Cache) *CachingItemReader {
+	return &CachingItemReader{reader: reader, cache: cache}
+}
+
+// ReadBlock implements index.ItemReader.
+func (r *CachingItemReader) ReadBlock() ([]index.Item, error) {
+	offset := r.reader.Offset()
+
+	if items, ok := r.cache.Get(r.reader.SegmentID(), offset); ok {
+		if err := r.reader.SkipBlock(len(items)); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	items, err := r.reader.ReadBlock()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(items) > 0 {
+		r.cache.Put(r.reader.SegmentID(), offset, items)
+	}
+	return items, err
+}
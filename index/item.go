@@ -4,6 +4,7 @@
 package index
 
 import (
+	"container/heap"
 	"go4.org/sort"
 	"io"
 	"math"
@@ -133,26 +134,25 @@ func (r *itemBufferReader) ReadBlock() (items []Item, err error) {
 
 // MergeItemReaders returns an ItemReader that merges the output of multiple source ItemReaders.
 func MergeItemReaders(readers ...ItemReader) ItemReader {
-	switch len(readers) {
+	var filtered []ItemReader
+	for _, reader := range readers {
+		if reader != nil {
+			filtered = append(filtered, reader)
+		}
+	}
+
+	switch len(filtered) {
 	case 0:
 		return nil
 	case 1:
-		return readers[0]
+		return filtered[0]
 	case 2:
-		if readers[0] == nil {
-			return readers[1]
-		}
-		if readers[1] == nil {
-			return readers[0]
-		}
-		reader := &multiItemReader{reader1: readers[0], reader2: readers[1]}
+		reader := &multiItemReader{reader1: filtered[0], reader2: filtered[1]}
 		reader.init()
 		return reader
+	default:
+		return newHeapItemReader(filtered)
 	}
-	mid := len(readers) / 2
-	reader1 := MergeItemReaders(readers[:mid]...)
-	reader2 := MergeItemReaders(readers[mid:]...)
-	return MergeItemReaders(reader1, reader2)
 }
 
 type multiItemReader struct {
@@ -227,3 +227,121 @@ func (r *multiItemReader) ReadBlock() (items []Item, err error) {
 	err = io.EOF
 	return
 }
+
+// headItem is a single entry in a heapItemReader's min-heap: the current head
+// item of one source reader, plus the index of that reader.
+type headItem struct {
+	item      Item
+	readerIdx int
+}
+
+type itemHeap []headItem
+
+func (h itemHeap) Len() int { return len(h) }
+func (h itemHeap) Less(i, j int) bool {
+	a, b := h[i].item, h[j].item
+	return a.Term < b.Term || (a.Term == b.Term && a.DocID < b.DocID)
+}
+func (h itemHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *itemHeap) Push(x interface{}) {
+	*h = append(*h, x.(headItem))
+}
+
+func (h *itemHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// heapItemReader merges three or more ItemReaders using a min-heap of their
+// current head items, popping one item at a time in ReadBlock and refilling
+// from the winning source reader as its block drains.
+type heapItemReader struct {
+	readers []ItemReader
+	blocks  [][]Item
+	heap    itemHeap
+	buf     []Item
+	started bool
+}
+
+func newHeapItemReader(readers []ItemReader) *heapItemReader {
+	return &heapItemReader{
+		readers: readers,
+		blocks:  make([][]Item, len(readers)),
+		buf:     make([]Item, 1024),
+	}
+}
+
+// fill reads another block from readers[i] when its current block is drained,
+// clearing the reader once it reaches EOF.
+func (r *heapItemReader) fill(i int) error {
+	for len(r.blocks[i]) == 0 && r.readers[i] != nil {
+		block, err := r.readers[i].ReadBlock()
+		r.blocks[i] = block
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			r.readers[i] = nil
+		}
+	}
+	return nil
+}
+
+func (r *heapItemReader) init() error {
+	r.started = true
+	for i := range r.readers {
+		if err := r.fill(i); err != nil {
+			return err
+		}
+		if len(r.blocks[i]) > 0 {
+			heap.Push(&r.heap, headItem{item: r.blocks[i][0], readerIdx: i})
+		}
+	}
+	return nil
+}
+
+func (r *heapItemReader) ReadBlock() (items []Item, err error) {
+	if !r.started {
+		if err := r.init(); err != nil {
+			return nil, err
+		}
+	}
+
+	items = r.buf[:0]
+	winner := -1
+	for len(r.heap) > 0 {
+		top := r.heap[0]
+		if winner == -1 {
+			winner = top.readerIdx
+		} else if top.readerIdx != winner {
+			break
+		}
+
+		items = append(items, top.item)
+		r.blocks[winner] = r.blocks[winner][1:]
+		if len(r.blocks[winner]) == 0 {
+			if err := r.fill(winner); err != nil {
+				return items, err
+			}
+		}
+		if len(r.blocks[winner]) > 0 {
+			r.heap[0] = headItem{item: r.blocks[winner][0], readerIdx: winner}
+			heap.Fix(&r.heap, 0)
+		} else {
+			heap.Pop(&r.heap)
+		}
+
+		if len(items) == cap(items) {
+			break
+		}
+	}
+
+	if len(items) == 0 {
+		err = io.EOF
+	}
+	return
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package index
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceItemReader is a test-only ItemReader that returns all of its items in
+// a single block.
+type sliceItemReader struct {
+	items []Item
+	done  bool
+}
+
+func (r *sliceItemReader) ReadBlock() ([]Item, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	r.done = true
+	return r.items, nil
+}
+
+func sortedItems(groups ...[]Item) []Item {
+	var all []Item
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Term < all[j].Term || (all[i].Term == all[j].Term && all[i].DocID < all[j].DocID)
+	})
+	return all
+}
+
+func TestMergeItemReaders_Empty(t *testing.T) {
+	assert.Nil(t, MergeItemReaders())
+	assert.Nil(t, MergeItemReaders(nil, nil))
+}
+
+func TestMergeItemReaders_Single(t *testing.T) {
+	items := makeTestItems(5)
+	r1 := &sliceItemReader{items: items}
+	merged := MergeItemReaders(r1, nil)
+	assert.Same(t, r1, merged)
+
+	got, err := ReadAllItems(merged)
+	require.NoError(t, err)
+	assert.Equal(t, items, got)
+}
+
+func TestMergeItemReaders_Two(t *testing.T) {
+	a := []Item{{Term: 1, DocID: 1}, {Term: 3, DocID: 1}}
+	b := []Item{{Term: 2, DocID: 1}, {Term: 4, DocID: 1}}
+
+	merged := MergeItemReaders(&sliceItemReader{items: a}, &sliceItemReader{items: b})
+	got, err := ReadAllItems(merged)
+	require.NoError(t, err)
+	assert.Equal(t, sortedItems(a, b), got)
+}
+
+func TestMergeItemReaders_ManyReaders(t *testing.T) {
+	groups := make([][]Item, 5)
+	var readers []ItemReader
+	for i := range groups {
+		group := []Item{
+			{Term: uint32(i), DocID: 0},
+			{Term: uint32(i + 100), DocID: 1},
+			{Term: uint32(i + 200), DocID: 2},
+		}
+		groups[i] = group
+		readers = append(readers, &sliceItemReader{items: group})
+	}
+
+	merged := MergeItemReaders(readers...)
+	_, ok := merged.(*heapItemReader)
+	require.True(t, ok, "expected MergeItemReaders to pick heapItemReader for 3+ readers")
+
+	got, err := ReadAllItems(merged)
+	require.NoError(t, err)
+	assert.Equal(t, sortedItems(groups...), got)
+}
+
+func TestMergeItemReaders_NilReadersFiltered(t *testing.T) {
+	a := makeTestItems(3)
+	b := makeTestItems(3)
+	c := makeTestItems(3)
+
+	merged := MergeItemReaders(nil, &sliceItemReader{items: a}, nil, &sliceItemReader{items: b}, nil, &sliceItemReader{items: c}, nil)
+	got, err := ReadAllItems(merged)
+	require.NoError(t, err)
+	assert.Equal(t, sortedItems(a, b, c), got)
+}
+
+// errItemReader returns one block of items and then a non-EOF error.
+type errItemReader struct {
+	items []Item
+	err   error
+	done  bool
+}
+
+func (r *errItemReader) ReadBlock() ([]Item, error) {
+	if r.done {
+		return nil, r.err
+	}
+	r.done = true
+	return r.items, nil
+}
+
+func TestHeapItemReader_ReadBlockReturnsPartialItemsOnFillError(t *testing.T) {
+	wantErr := errors.New("boom")
+
+	// One block each from three readers, so the heap picks the winning
+	// reader's single item and then calls fill on it, which fails.
+	r1 := &errItemReader{items: []Item{{Term: 1, DocID: 1}}, err: wantErr}
+	r2 := &sliceItemReader{items: []Item{{Term: 2, DocID: 1}}}
+	r3 := &sliceItemReader{items: []Item{{Term: 3, DocID: 1}}}
+
+	merged := newHeapItemReader([]ItemReader{r1, r2, r3})
+	items, err := merged.ReadBlock()
+	require.Error(t, err)
+	assert.Same(t, wantErr, err)
+	assert.Equal(t, []Item{{Term: 1, DocID: 1}}, items, "partial items accumulated before the fill error should still be returned")
+}
@@ -0,0 +1,292 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package index
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/acoustid/go-acoustid/index/vfs"
+)
+
+// Pack files are the on-disk segment format: a 4-byte magic and version header,
+// a 256-entry fanout table indexed by the top byte of Item.Term holding cumulative
+// item counts, a sorted [Term, DocID] table split into CRC32C-protected blocks, and
+// a trailing SHA256 of everything that came before it. The layout is modeled on the
+// one git uses for ".idx" v2 files, so a term lookup can jump straight to the block
+// that contains it instead of scanning the file.
+const (
+	packMagic      = "ACIX"
+	packVersion    = 1
+	packHeaderSize = 8 // magic + version
+	packFanoutSize = 256
+	packBlockItems = 1024
+	packItemSize   = 8                               // Term + DocID, both uint32
+	packBlockSize  = packBlockItems*packItemSize + 4 // + CRC32C
+)
+
+var packCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrInvalidPackFile is returned when a file does not look like a pack file at all.
+	ErrInvalidPackFile = errors.New("invalid pack file")
+	// ErrPackFileCorrupt is returned when a pack file fails a CRC32C or SHA256 check.
+	ErrPackFileCorrupt = errors.New("pack file corrupt")
+)
+
+// PackWriter writes an ItemReader out to a pack file.
+type PackWriter struct {
+	fs   vfs.FileSystem
+	name string
+}
+
+// NewPackWriter creates a PackWriter that will write name into fs.
+func NewPackWriter(fs vfs.FileSystem, name string) *PackWriter {
+	return &PackWriter{fs: fs, name: name}
+}
+
+// WriteFrom drains reader and writes its items to an atomically-created pack file.
+// The file only becomes visible under its final name once it has been fully
+// written, checksummed and synced to disk.
+func (w *PackWriter) WriteFrom(reader ItemReader) error {
+	items, err := ReadAllItems(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to read items")
+	}
+
+	out, err := w.fs.CreateAtomicFile(w.name)
+	if err != nil {
+		return errors.Wrap(err, "failed to create output file")
+	}
+	defer out.Close()
+
+	digest := sha256.New()
+	dst := io.MultiWriter(out, digest)
+
+	var header [packHeaderSize]byte
+	copy(header[:4], packMagic)
+	binary.BigEndian.PutUint32(header[4:], packVersion)
+	if _, err := dst.Write(header[:]); err != nil {
+		return errors.Wrap(err, "failed to write header")
+	}
+
+	var fanout [packFanoutSize]uint32
+	for _, item := range items {
+		fanout[item.Term>>24]++
+	}
+	for i := 1; i < packFanoutSize; i++ {
+		fanout[i] += fanout[i-1]
+	}
+	var fanoutBuf [packFanoutSize * 4]byte
+	for i, count := range fanout {
+		binary.BigEndian.PutUint32(fanoutBuf[i*4:], count)
+	}
+	if _, err := dst.Write(fanoutBuf[:]); err != nil {
+		return errors.Wrap(err, "failed to write fanout table")
+	}
+
+	block := make([]byte, 0, packBlockItems*packItemSize)
+	for i := 0; i < len(items); i += packBlockItems {
+		end := i + packBlockItems
+		if end > len(items) {
+			end = len(items)
+		}
+		block = block[:0]
+		for _, item := range items[i:end] {
+			var b [packItemSize]byte
+			binary.BigEndian.PutUint32(b[:4], item.Term)
+			binary.BigEndian.PutUint32(b[4:], item.DocID)
+			block = append(block, b[:]...)
+		}
+		if _, err := dst.Write(block); err != nil {
+			return errors.Wrap(err, "failed to write block")
+		}
+		var crcBuf [4]byte
+		binary.BigEndian.PutUint32(crcBuf[:], crc32.Checksum(block, packCRCTable))
+		if _, err := dst.Write(crcBuf[:]); err != nil {
+			return errors.Wrap(err, "failed to write block checksum")
+		}
+	}
+
+	if _, err := out.Write(digest.Sum(nil)); err != nil {
+		return errors.Wrap(err, "failed to write trailer")
+	}
+
+	if err := out.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync output file")
+	}
+
+	return out.Commit()
+}
+
+// PackReader reads a pack file written by PackWriter. It implements ItemReader
+// and additionally provides a Seek fast-path based on the fanout table.
+type PackReader struct {
+	f        vfs.InputFile
+	name     string
+	fanout   [packFanoutSize]uint32
+	numItems int
+	pos      int
+}
+
+// OpenPackReader opens name in fs and reads its header and fanout table.
+// It does not verify the trailing SHA256 of the file, since doing so
+// requires a full read of the file and would defeat the point of the
+// fanout-based Seek fast-path; call Verify explicitly when that's wanted
+// (e.g. during a background integrity scan).
+func OpenPackReader(fs vfs.FileSystem, name string) (*PackReader, error) {
+	f, err := fs.OpenFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open file")
+	}
+	r := &PackReader{f: f, name: name}
+	if err := r.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// SegmentID identifies the segment this reader reads from, for use as a cache key.
+func (r *PackReader) SegmentID() string {
+	return r.name
+}
+
+// Offset returns the item position that will be read by the next ReadBlock call.
+func (r *PackReader) Offset() int {
+	return r.pos
+}
+
+// SkipBlock advances the reader past a block of n items without decoding or
+// CRC-checking it, for callers that already have its contents from elsewhere
+// (e.g. a block cache).
+func (r *PackReader) SkipBlock(n int) error {
+	if _, err := r.f.Seek(int64(n*packItemSize+4), io.SeekCurrent); err != nil {
+		return errors.Wrap(err, "failed to seek")
+	}
+	r.pos += n
+	return nil
+}
+
+func (r *PackReader) readHeader() error {
+	var header [packHeaderSize]byte
+	if _, err := io.ReadFull(r.f, header[:]); err != nil {
+		return errors.Wrap(ErrInvalidPackFile, "failed to read header")
+	}
+	if string(header[:4]) != packMagic {
+		return errors.Wrap(ErrInvalidPackFile, "bad magic")
+	}
+	if binary.BigEndian.Uint32(header[4:]) != packVersion {
+		return errors.Wrap(ErrInvalidPackFile, "unsupported version")
+	}
+
+	var fanoutBuf [packFanoutSize * 4]byte
+	if _, err := io.ReadFull(r.f, fanoutBuf[:]); err != nil {
+		return errors.Wrap(ErrInvalidPackFile, "failed to read fanout table")
+	}
+	for i := range r.fanout {
+		r.fanout[i] = binary.BigEndian.Uint32(fanoutBuf[i*4:])
+	}
+	r.numItems = int(r.fanout[packFanoutSize-1])
+	return nil
+}
+
+// Close releases the underlying file.
+func (r *PackReader) Close() error {
+	return r.f.Close()
+}
+
+// Verify streams the whole file through SHA256 and compares the result
+// against the trailer written by PackWriter. Unlike the per-block CRC32Cs,
+// which are only checked as blocks are read, this detects corruption
+// anywhere in the file in one pass. When the check passes, it rewinds the
+// reader to the first block before returning, so reading can start over;
+// when it fails, the reader's position is left undefined.
+func (r *PackReader) Verify() error {
+	size, err := r.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return errors.Wrap(err, "failed to seek to end")
+	}
+
+	dataSize := size - sha256.Size
+	if dataSize < int64(packHeaderSize+packFanoutSize*4) {
+		return errors.Wrap(ErrInvalidPackFile, "file too small")
+	}
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, io.NewSectionReader(r.f, 0, dataSize)); err != nil {
+		return errors.Wrap(err, "failed to read file")
+	}
+
+	trailer := make([]byte, sha256.Size)
+	if _, err := r.f.ReadAt(trailer, dataSize); err != nil {
+		return errors.Wrap(err, "failed to read trailer")
+	}
+
+	if !bytes.Equal(digest.Sum(nil), trailer) {
+		return errors.Wrap(ErrPackFileCorrupt, "trailer checksum mismatch")
+	}
+
+	blockStart := int64(packHeaderSize + packFanoutSize*4)
+	if _, err := r.f.Seek(blockStart, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek")
+	}
+	r.pos = 0
+	return nil
+}
+
+// Seek positions the reader at the start of the block that contains the first
+// item whose Term could equal term, using a single 4-byte fanout lookup rather
+// than scanning the file from the beginning.
+func (r *PackReader) Seek(term uint32) error {
+	var start uint32
+	if b := term >> 24; b > 0 {
+		start = r.fanout[b-1]
+	}
+	block := int(start) / packBlockItems
+	offset := int64(packHeaderSize+packFanoutSize*4) + int64(block)*packBlockSize
+	if _, err := r.f.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek")
+	}
+	r.pos = block * packBlockItems
+	return nil
+}
+
+// ReadBlock reads the next block of items, verifying its CRC32C.
+func (r *PackReader) ReadBlock() (items []Item, err error) {
+	if r.pos >= r.numItems {
+		return nil, io.EOF
+	}
+
+	n := packBlockItems
+	if r.pos+n > r.numItems {
+		n = r.numItems - r.pos
+	}
+
+	block := make([]byte, n*packItemSize)
+	if _, err := io.ReadFull(r.f, block); err != nil {
+		return nil, errors.Wrap(ErrPackFileCorrupt, "failed to read block")
+	}
+
+	var crcBuf [4]byte
+	if _, err := io.ReadFull(r.f, crcBuf[:]); err != nil {
+		return nil, errors.Wrap(ErrPackFileCorrupt, "failed to read block checksum")
+	}
+	if crc32.Checksum(block, packCRCTable) != binary.BigEndian.Uint32(crcBuf[:]) {
+		return nil, errors.Wrap(ErrPackFileCorrupt, "block checksum mismatch")
+	}
+
+	items = make([]Item, n)
+	for i := range items {
+		items[i].Term = binary.BigEndian.Uint32(block[i*packItemSize:])
+		items[i].DocID = binary.BigEndian.Uint32(block[i*packItemSize+4:])
+	}
+	r.pos += n
+	return items, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2016  Lukas Lalinsky
+// Distributed under the MIT license, see the LICENSE file for details.
+
+package index
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acoustid/go-acoustid/index/vfs"
+)
+
+func makeTestItems(n int) []Item {
+	items := make([]Item, n)
+	for i := range items {
+		items[i] = Item{Term: uint32(i * 7919), DocID: uint32(i)}
+	}
+	return items
+}
+
+func writeTestPack(t *testing.T, fs vfs.FileSystem, name string, items []Item) {
+	t.Helper()
+	var buf ItemBuffer
+	for _, item := range items {
+		buf.Add(item.DocID, []uint32{item.Term})
+	}
+	require.NoError(t, NewPackWriter(fs, name).WriteFrom(buf.Reader()))
+}
+
+// flipByte inverts a single byte in name, leaving the rest of the file intact.
+func flipByte(t *testing.T, fs vfs.FileSystem, name string, offset int) {
+	t.Helper()
+	f, err := fs.OpenFile(name)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data[offset] ^= 0xff
+
+	out, err := fs.CreateFile(name, true)
+	require.NoError(t, err)
+	_, err = out.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+}
+
+func TestPackFile_RoundTrip(t *testing.T) {
+	fs := vfs.NewMemFS()
+	items := makeTestItems(3000)
+	writeTestPack(t, fs, "seg", items)
+
+	r, err := OpenPackReader(fs, "seg")
+	require.NoError(t, err)
+	defer r.Close()
+
+	got, err := ReadAllItems(r)
+	require.NoError(t, err)
+	assert.Equal(t, items, got)
+}
+
+func TestPackFile_Seek(t *testing.T) {
+	fs := vfs.NewMemFS()
+	items := makeTestItems(3000)
+	writeTestPack(t, fs, "seg", items)
+
+	r, err := OpenPackReader(fs, "seg")
+	require.NoError(t, err)
+	defer r.Close()
+
+	target := items[len(items)/2].Term
+	require.NoError(t, r.Seek(target))
+
+	block, err := r.ReadBlock()
+	require.NoError(t, err)
+	require.NotEmpty(t, block)
+	assert.LessOrEqual(t, block[0].Term>>24, target>>24)
+}
+
+func TestPackFile_CorruptedBlock(t *testing.T) {
+	fs := vfs.NewMemFS()
+	items := makeTestItems(10)
+	writeTestPack(t, fs, "seg", items)
+
+	// The first item lives just after the header and fanout table.
+	flipByte(t, fs, "seg", packHeaderSize+packFanoutSize*4)
+
+	r, err := OpenPackReader(fs, "seg")
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, err = ReadAllItems(r)
+	assert.Equal(t, ErrPackFileCorrupt, errors.Cause(err))
+}
+
+func TestPackFile_CorruptedTrailer(t *testing.T) {
+	fs := vfs.NewMemFS()
+	items := makeTestItems(10)
+	writeTestPack(t, fs, "seg", items)
+
+	f, err := fs.OpenFile("seg")
+	require.NoError(t, err)
+	size, err := f.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	flipByte(t, fs, "seg", int(size)-1)
+
+	r, err := OpenPackReader(fs, "seg")
+	require.NoError(t, err)
+	defer r.Close()
+
+	err = r.Verify()
+	require.Error(t, err)
+	assert.Equal(t, ErrPackFileCorrupt, errors.Cause(err))
+}
+
+func TestPackFile_Verify_OK(t *testing.T) {
+	fs := vfs.NewMemFS()
+	items := makeTestItems(3000)
+	writeTestPack(t, fs, "seg", items)
+
+	r, err := OpenPackReader(fs, "seg")
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.Verify())
+
+	// Verify rewinds the reader, so a normal read still works afterwards.
+	got, err := ReadAllItems(r)
+	require.NoError(t, err)
+	assert.Equal(t, items, got)
+}
@@ -0,0 +1,220 @@
+package vfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memFile is the shared state of a single named file in a MemFS. Readers
+// opened against it capture their own snapshot of data (see OpenFile), so
+// they keep seeing the old contents even after the file is overwritten or
+// removed: overwriting never mutates a previously-read data slice in place,
+// and removal only ever unlinks the name from fs.files, never touches a
+// memFile that a reader already holds.
+type memFile struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// MemFS is an in-memory implementation of FileSystem, useful for tests and
+// ephemeral indexes that don't need to survive process restarts.
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string]*memFile
+
+	locksMu sync.Mutex
+	locks   map[string]struct{}
+}
+
+// NewMemFS creates an empty in-memory FileSystem.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string]*memFile),
+		locks: make(map[string]struct{}),
+	}
+}
+
+func (fs *MemFS) Path() string {
+	return fmt.Sprintf("memfs://%p", fs)
+}
+
+func (fs *MemFS) Close() error {
+	return nil
+}
+
+func (fs *MemFS) Lock(name string) (io.Closer, error) {
+	fs.locksMu.Lock()
+	defer fs.locksMu.Unlock()
+
+	if _, locked := fs.locks[name]; locked {
+		return nil, ErrLocked
+	}
+	fs.locks[name] = struct{}{}
+	return &memLock{fs: fs, name: name}, nil
+}
+
+type memLock struct {
+	fs   *MemFS
+	name string
+}
+
+func (l *memLock) Close() error {
+	l.fs.locksMu.Lock()
+	delete(l.fs.locks, l.name)
+	l.fs.locksMu.Unlock()
+	return nil
+}
+
+func (fs *MemFS) ReadDir() ([]os.FileInfo, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	infos := make([]os.FileInfo, 0, len(fs.files))
+	for name, f := range fs.files {
+		f.mu.Lock()
+		infos = append(infos, &memFileInfo{name: name, size: int64(len(f.data))})
+		f.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi *memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *memFileInfo) IsDir() bool        { return false }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+func (fs *MemFS) OpenFile(name string) (InputFile, error) {
+	fs.mu.RLock()
+	f, ok := fs.files[name]
+	fs.mu.RUnlock()
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	f.mu.Lock()
+	data := f.data
+	f.mu.Unlock()
+
+	return &memReader{r: bytes.NewReader(data)}, nil
+}
+
+type memReader struct {
+	r *bytes.Reader
+}
+
+func (r *memReader) Read(p []byte) (int, error)                   { return r.r.Read(p) }
+func (r *memReader) ReadAt(p []byte, off int64) (int, error)      { return r.r.ReadAt(p, off) }
+func (r *memReader) Seek(offset int64, whence int) (int64, error) { return r.r.Seek(offset, whence) }
+
+func (r *memReader) Close() error {
+	return nil
+}
+
+func (fs *MemFS) CreateFile(name string, overwrite bool) (OutputFile, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.files[name]; exists && !overwrite {
+		return nil, os.ErrExist
+	}
+
+	f := &memFile{}
+	fs.files[name] = f
+	return &memOutputFile{f: f}, nil
+}
+
+type memOutputFile struct {
+	f   *memFile
+	buf bytes.Buffer
+}
+
+func (o *memOutputFile) Write(p []byte) (int, error) {
+	n, err := o.buf.Write(p)
+	o.f.mu.Lock()
+	o.f.data = append([]byte(nil), o.buf.Bytes()...)
+	o.f.mu.Unlock()
+	return n, err
+}
+
+func (o *memOutputFile) Sync() error {
+	return nil
+}
+
+func (o *memOutputFile) Close() error {
+	return nil
+}
+
+func (fs *MemFS) CreateAtomicFile(name string) (AtomicOutputFile, error) {
+	return &memAtomicOutputFile{fs: fs, name: name}, nil
+}
+
+type memAtomicOutputFile struct {
+	fs        *MemFS
+	name      string
+	buf       bytes.Buffer
+	committed bool
+}
+
+func (o *memAtomicOutputFile) Write(p []byte) (int, error) {
+	if o.committed {
+		return 0, ErrAlreadyCommitted
+	}
+	return o.buf.Write(p)
+}
+
+func (o *memAtomicOutputFile) Sync() error {
+	return nil
+}
+
+func (o *memAtomicOutputFile) Close() error {
+	return nil
+}
+
+func (o *memAtomicOutputFile) Commit() error {
+	if o.committed {
+		return ErrAlreadyCommitted
+	}
+	o.committed = true
+
+	o.fs.mu.Lock()
+	o.fs.files[o.name] = &memFile{data: append([]byte(nil), o.buf.Bytes()...)}
+	o.fs.mu.Unlock()
+	return nil
+}
+
+func (fs *MemFS) Rename(newname, oldname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[oldname]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(fs.files, oldname)
+	fs.files[newname] = f
+	return nil
+}
+
+func (fs *MemFS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.files[name]; !ok {
+		return os.ErrNotExist
+	}
+	delete(fs.files, name)
+	return nil
+}
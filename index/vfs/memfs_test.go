@@ -0,0 +1,147 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemFS_CreateAtomicFile(t *testing.T) {
+	fs := NewMemFS()
+
+	out, err := fs.CreateAtomicFile("a")
+	require.NoError(t, err)
+	_, err = out.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	_, err = fs.OpenFile("a")
+	assert.True(t, IsNotExist(err), "file should not be visible before Commit")
+
+	require.NoError(t, out.Commit())
+	assert.Equal(t, ErrAlreadyCommitted, out.Commit())
+
+	f, err := fs.OpenFile("a")
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestMemFS_RemoveKeepsOpenReaderContents(t *testing.T) {
+	fs := NewMemFS()
+
+	out, err := fs.CreateAtomicFile("a")
+	require.NoError(t, err)
+	_, err = out.Write([]byte("original"))
+	require.NoError(t, err)
+	require.NoError(t, out.Commit())
+
+	f, err := fs.OpenFile("a")
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, fs.Remove("a"))
+
+	_, err = fs.OpenFile("a")
+	assert.True(t, IsNotExist(err), "removed file should no longer be visible to new opens")
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data), "a reader opened before Remove should keep seeing the old contents")
+}
+
+func TestMemFS_CreateFileOverwrite(t *testing.T) {
+	fs := NewMemFS()
+
+	out, err := fs.CreateFile("a", false)
+	require.NoError(t, err)
+	_, err = out.Write([]byte("v1"))
+	require.NoError(t, err)
+
+	_, err = fs.CreateFile("a", false)
+	assert.True(t, IsExist(err), "creating an existing file without overwrite should fail")
+
+	out2, err := fs.CreateFile("a", true)
+	require.NoError(t, err)
+	_, err = out2.Write([]byte("v2"))
+	require.NoError(t, err)
+
+	f, err := fs.OpenFile("a")
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+}
+
+func TestMemFS_Lock(t *testing.T) {
+	fs := NewMemFS()
+
+	l1, err := fs.Lock("seg")
+	require.NoError(t, err)
+
+	_, err = fs.Lock("seg")
+	assert.True(t, IsLocked(err))
+
+	require.NoError(t, l1.Close())
+
+	l2, err := fs.Lock("seg")
+	require.NoError(t, err)
+	defer l2.Close()
+}
+
+func TestMemFS_Rename(t *testing.T) {
+	fs := NewMemFS()
+
+	out, err := fs.CreateAtomicFile("a")
+	require.NoError(t, err)
+	_, err = out.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, out.Commit())
+
+	require.NoError(t, fs.Rename("b", "a"))
+
+	_, err = fs.OpenFile("a")
+	assert.True(t, IsNotExist(err))
+
+	f, err := fs.OpenFile("b")
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	// Renaming over an existing name replaces it atomically.
+	out2, err := fs.CreateAtomicFile("c")
+	require.NoError(t, err)
+	_, err = out2.Write([]byte("other"))
+	require.NoError(t, err)
+	require.NoError(t, out2.Commit())
+
+	require.NoError(t, fs.Rename("c", "b"))
+	f2, err := fs.OpenFile("c")
+	require.NoError(t, err)
+	defer f2.Close()
+	data2, err := ioutil.ReadAll(f2)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data2))
+}
+
+func TestMemFS_ReadDirSorted(t *testing.T) {
+	fs := NewMemFS()
+	for _, name := range []string{"c", "a", "b"} {
+		out, err := fs.CreateAtomicFile(name)
+		require.NoError(t, err)
+		require.NoError(t, out.Commit())
+	}
+
+	entries, err := fs.ReadDir()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{entries[0].Name(), entries[1].Name(), entries[2].Name()})
+}